@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Агент (вычислитель). Каждый агент регистрируется, как и внешний процесс
+// бы регистрировался через /agents/register, затем читает готовые
+// подзадачи из общего канала, имитирует вычисление и передаёт результат
+// планировщику, который сам разберётся, разблокировала ли эта подзадача
+// соседей по дереву.
+func startAgents(numAgents int) {
+	for i := 0; i < numAgents; i++ {
+		agent := registerAgent()
+		go runAgent(agent)
+	}
+}
+
+func runAgent(agent *Agent) {
+	stopHeartbeat := startSelfHeartbeat(agent)
+	defer stopHeartbeat()
+
+	// Канал будит агента, но кто именно получит подзадачу в работу решает
+	// ReserveSubtask — это гарантирует, что при восстановлении после сбоя
+	// два агента не возьмут одну и ту же подзадачу дважды.
+	for range taskChannel {
+		sub, ok, err := taskStore.ReserveSubtask(agent.ID, subtaskLeaseDuration)
+		if err != nil || !ok {
+			continue
+		}
+
+		ctx := taskContextFor(sub.TaskID)
+		if ctx.Err() != nil {
+			abandonSubtask(sub.ID)
+			continue
+		}
+
+		result, err := performOperation(ctx, sub.Op, sub.LeftValue, sub.RightValue)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Дедлайн истёк или задачу отменили прямо во время
+				// вычисления — подзадачу дальше не двигаем,
+				// finalizeCanceled уже позаботится о статусе самой задачи.
+				abandonSubtask(sub.ID)
+			} else {
+				// Операцию в принципе невозможно выполнить (например,
+				// деление на ноль) — это не временная отмена, а фатальная
+				// ошибка самой задачи.
+				failSubtask(sub.ID, err)
+			}
+			continue
+		}
+		onSubtaskComplete(sub.ID, result)
+	}
+}
+
+// startSelfHeartbeat заводит для локального агента тот же heartbeat,
+// который внешний процесс слал бы через POST /agents/{id}/heartbeat.
+func startSelfHeartbeat(agent *Agent) (stop func()) {
+	ticker := time.NewTicker(heartbeatTimeout / 3)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				touchHeartbeat(agent.ID)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// performOperation выполняет одну бинарную операцию, предварительно
+// "отработав" её модельную длительность из operations. Если ctx отменяется
+// раньше, чем длительность истекла (дедлайн задачи или DELETE /tasks/{id}),
+// операция прерывается и возвращает ctx.Err().
+func performOperation(ctx context.Context, op byte, a, b float64) (float64, error) {
+	var result float64
+	switch op {
+	case '+':
+		result = a + b
+	case '-':
+		result = a - b
+	case '*':
+		result = a * b
+	case '/':
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		result = a / b
+	default:
+		return 0, fmt.Errorf("unknown operator %q", op)
+	}
+	duration := operationDuration(op)
+
+	select {
+	case <-time.After(duration):
+		return result, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Agent — вычислитель, зарегистрированный в оркестраторе: как локальные
+// горутины из startAgents, так и внешние процессы, приходящие через
+// /agents/register.
+type Agent struct {
+	ID            int       `json:"id"`
+	Token         string    `json:"-"`
+	RegisteredAt  time.Time `json:"registered_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// heartbeatTimeout — если агент дольше этого времени не шлёт heartbeat,
+// считаем его пропавшим и отбираем у него лизинг подзадачи.
+const heartbeatTimeout = 30 * time.Second
+
+var (
+	agentsMutex sync.Mutex
+	agents      = make(map[int]*Agent)
+	nextAgentID = 1
+)
+
+func registerAgent() *Agent {
+	agentsMutex.Lock()
+	defer agentsMutex.Unlock()
+
+	agent := &Agent{
+		ID:            nextAgentID,
+		Token:         generateToken(),
+		RegisteredAt:  time.Now(),
+		LastHeartbeat: time.Now(),
+	}
+	agents[agent.ID] = agent
+	nextAgentID++
+	return agent
+}
+
+func generateToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func agentByToken(token string) (*Agent, bool) {
+	agentsMutex.Lock()
+	defer agentsMutex.Unlock()
+	for _, agent := range agents {
+		if agent.Token == token {
+			return agent, true
+		}
+	}
+	return nil, false
+}
+
+func agentSnapshot(id int) (Agent, bool) {
+	agentsMutex.Lock()
+	defer agentsMutex.Unlock()
+	agent, ok := agents[id]
+	if !ok {
+		return Agent{}, false
+	}
+	return *agent, true
+}
+
+func touchHeartbeat(id int) bool {
+	agentsMutex.Lock()
+	defer agentsMutex.Unlock()
+	agent, ok := agents[id]
+	if !ok {
+		return false
+	}
+	agent.LastHeartbeat = time.Now()
+	return true
+}
+
+func registerAgentHandler(w http.ResponseWriter, r *http.Request) {
+	agent := registerAgent()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"agent_id": agent.ID,
+		"token":    agent.Token,
+	})
+}
+
+func agentHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid agent ID", http.StatusBadRequest)
+		return
+	}
+	agent, ok := agentByToken(r.Header.Get("X-Agent-Token"))
+	if !ok || agent.ID != id {
+		http.Error(w, "Invalid agent token", http.StatusUnauthorized)
+		return
+	}
+	touchHeartbeat(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func listAgentsHandler(w http.ResponseWriter, r *http.Request) {
+	agentsMutex.Lock()
+	list := make([]*Agent, 0, len(agents))
+	for _, agent := range agents {
+		list = append(list, agent)
+	}
+	agentsMutex.Unlock()
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// operationConfigEntry — одна запись в JSON-файле конфигурации операций.
+// Длительность там пишется в человекочитаемом виде ("20s"), в отличие от
+// наносекунд в HTTP API, потому что этот файл правит оператор руками.
+type operationConfigEntry struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+}
+
+// parseOperationsConfig разбирает и валидирует JSON-конфиг операций:
+// должны быть перечислены все операции из operationOrder, и все с
+// положительной длительностью.
+func parseOperationsConfig(data []byte) (map[string]time.Duration, error) {
+	var entries []operationConfigEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse operations config: %w", err)
+	}
+
+	cfg := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		duration, err := time.ParseDuration(entry.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("operation %q: invalid duration %q: %w", entry.Name, entry.Duration, err)
+		}
+		cfg[entry.Name] = duration
+	}
+
+	if err := validateOperationsConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validateOperationsConfig проверяет, что все четыре операции присутствуют
+// и имеют положительную длительность.
+func validateOperationsConfig(cfg map[string]time.Duration) error {
+	for _, name := range operationOrder {
+		duration, ok := cfg[name]
+		if !ok {
+			return fmt.Errorf("missing duration for operation %q", name)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("duration for operation %q must be positive", name)
+		}
+	}
+	return nil
+}
+
+// loadOperationsConfigFile читает и валидирует конфиг операций из path.
+func loadOperationsConfigFile(path string) (map[string]time.Duration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read operations config %s: %w", path, err)
+	}
+	return parseOperationsConfig(data)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Kulibyka/YandexL_1_prj/store"
+)
+
+// taskContext связывает задачу с её context.Context: отменяется либо по
+// истечении Task.Deadline, либо явным вызовом DELETE /tasks/{id}.
+type taskContext struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var (
+	taskContextsMu sync.Mutex
+	taskContexts   = make(map[int]*taskContext)
+)
+
+// registerTaskContext заводит context для задачи и горутину, которая по
+// его отмене переводит задачу в статус "canceled".
+func registerTaskContext(task *store.Task) context.Context {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if !task.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(context.Background(), task.Deadline)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	taskContextsMu.Lock()
+	taskContexts[task.ID] = &taskContext{ctx: ctx, cancel: cancel}
+	taskContextsMu.Unlock()
+
+	go watchTaskContext(task.ID, ctx)
+	return ctx
+}
+
+// taskContextFor возвращает context подзадачи по её задаче; если задача
+// уже выполнена и её context убран из реестра, отдаёт фоновый — то есть
+// "никогда не отменён".
+func taskContextFor(taskID int) context.Context {
+	taskContextsMu.Lock()
+	defer taskContextsMu.Unlock()
+	if tc, ok := taskContexts[taskID]; ok {
+		return tc.ctx
+	}
+	return context.Background()
+}
+
+// cancelTask отменяет context задачи, если она ещё выполняется.
+func cancelTask(taskID int) bool {
+	taskContextsMu.Lock()
+	tc, ok := taskContexts[taskID]
+	taskContextsMu.Unlock()
+	if !ok || tc.ctx.Err() != nil {
+		return false
+	}
+	tc.cancel()
+	return true
+}
+
+// releaseTaskContext отменяет context уже успешно завершившейся задачи,
+// чтобы не оставлять висящую горутину watchTaskContext.
+func releaseTaskContext(taskID int) {
+	taskContextsMu.Lock()
+	tc, ok := taskContexts[taskID]
+	taskContextsMu.Unlock()
+	if ok {
+		tc.cancel()
+	}
+}
+
+func watchTaskContext(taskID int, ctx context.Context) {
+	<-ctx.Done()
+	finalizeCanceled(taskID, ctx.Err())
+
+	// Context отменённой задачи остаётся в реестре, чтобы taskContextFor и
+	// дальше отдавал его для её ещё стоящих в очереди подзадач; убирается
+	// только context задачи, завершившейся иначе.
+	if task, found, err := taskStore.GetTask(taskID); err == nil && found && task.Status == "canceled" {
+		return
+	}
+
+	taskContextsMu.Lock()
+	delete(taskContexts, taskID)
+	taskContextsMu.Unlock()
+}
+
+// finalizeCanceled помечает задачу отменённой, если она ещё не успела
+// завершиться сама. CancelTask делает это одной транзакцией, заново читая
+// задачу по ключу, поэтому конкурентное завершение подзадачи, пришедшееся
+// на тот же момент, что и срабатывание дедлайна, не теряется под слепой
+// перезаписью устаревшего снимка. Уже стоящие в очереди подзадачи никто
+// специально не трогает: агент, забрав такую подзадачу, увидит отменённый
+// context и не станет её выполнять (см. runAgent).
+func finalizeCanceled(taskID int, err error) {
+	taskStore.CancelTask(taskID, err.Error())
+}
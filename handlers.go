@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Kulibyka/YandexL_1_prj/store"
+)
+
+func addTaskHandler(w http.ResponseWriter, r *http.Request) {
+	// Распаковываем JSON-данные из запроса
+	var task store.Task
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	// Добавляем задачу в хранилище. nextTaskID — общий счётчик для всех
+	// запросов, поэтому его чтение и инкремент защищены schedulerMutex (тем
+	// же, что охраняет его переинициализацию в loadState) — иначе два
+	// конкурентных POST /tasks/add могут получить один и тот же ID и
+	// перезаписать задачи друг друга в taskStore.
+	schedulerMutex.Lock()
+	task.ID = nextTaskID
+	nextTaskID++
+	schedulerMutex.Unlock()
+	task.Status = "queued"
+	if err := taskStore.CreateTask(&task); err != nil {
+		http.Error(w, "Failed to persist task", http.StatusInternalServerError)
+		return
+	}
+
+	// Разбираем выражение на подзадачи и ставим готовые в очередь
+	scheduleTask(&task)
+
+	// Отвечаем клиенту с ID задачи
+	json.NewEncoder(w).Encode(map[string]int{"task_id": task.ID})
+}
+
+func getTaskResultHandler(w http.ResponseWriter, r *http.Request) {
+	// Извлекаем ID задачи из URL
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/")
+	taskID, err := strconv.Atoi(segments[0])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	task, found, err := taskStore.GetTask(taskID)
+	if err != nil {
+		http.Error(w, "Failed to read task", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	switch task.Status {
+	case "completed":
+		// Отвечаем клиенту с результатом задачи
+		json.NewEncoder(w).Encode(map[string]float64{"result": task.Result})
+	case "failed", "canceled":
+		json.NewEncoder(w).Encode(map[string]string{"status": task.Status, "error": task.Error})
+	default:
+		// Задача еще не завершена — отправляем сообщение ожидания
+		w.WriteHeader(http.StatusAccepted) // Статус код 202 Accepted
+		json.NewEncoder(w).Encode(map[string]string{"status": "Task is not completed yet"})
+	}
+}
+
+// cancelTaskHandler — DELETE /tasks/{id}: досрочно отменяет ещё
+// выполняющуюся задачу, не дожидаясь её дедлайна.
+func cancelTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	if !cancelTask(taskID) {
+		http.Error(w, "Task not found or already finished", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func listTasksHandler(w http.ResponseWriter, r *http.Request) {
+	// Отвечаем клиенту со списком задач
+	tasks, err := taskStore.ListTasks()
+	if err != nil {
+		http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(tasks)
+}
+
+func getOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	// Отправляем клиенту список доступных операций со временем их выполнения
+	json.NewEncoder(w).Encode(listOperations())
+}
+
+type operationUpdate struct {
+	Duration time.Duration `json:"duration"`
+}
+
+// updateOperationHandler — PUT /operations/{name}: меняет длительность
+// одной операции, не трогая остальные.
+func updateOperationHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var upd operationUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if upd.Duration <= 0 {
+		http.Error(w, "Duration must be positive", http.StatusBadRequest)
+		return
+	}
+	if !setOperationDuration(name, upd.Duration) {
+		http.Error(w, "Unknown operation", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// replaceOperationsHandler — PUT /operations: атомарно подменяет весь
+// каталог операций целиком (в отличие от точечного updateOperationHandler),
+// тем же путём, которым конфиг перечитывается по SIGHUP.
+func replaceOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	var ops []Operation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	cfg := make(map[string]time.Duration, len(ops))
+	for _, op := range ops {
+		cfg[op.Name] = op.Duration
+	}
+	if err := validateOperationsConfig(cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	replaceOperations(cfg)
+	log.Printf("operations config replaced via PUT /operations: %+v", cfg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getTaskForExecutionHandler(w http.ResponseWriter, r *http.Request) {
+	agent, ok := agentByToken(r.Header.Get("X-Agent-Token"))
+	if !ok {
+		http.Error(w, "Invalid agent token", http.StatusUnauthorized)
+		return
+	}
+
+	// Канал сигнализирует, что в хранилище появилась готовая подзадача;
+	// саму подзадачу атомарно резервируем через taskStore и закрепляем за
+	// этим агентом на время subtaskLeaseDuration, чтобы не отдать её
+	// одновременно кому-то ещё.
+	<-taskChannel
+	sub, ok, err := taskStore.ReserveSubtask(agent.ID, subtaskLeaseDuration)
+	if err != nil {
+		http.Error(w, "Failed to reserve subtask", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "No subtask available", http.StatusNotFound)
+		return
+	}
+
+	// Владеющая задача тем временем могла быть отменена через
+	// DELETE /tasks/{id} или истечь по дедлайну — внешнему агенту, в
+	// отличие от runAgent, без этой проверки было бы нечем узнать об этом,
+	// и он продолжил бы считать уже отменённую подзадачу.
+	if taskContextFor(sub.TaskID).Err() != nil {
+		abandonSubtask(sub.ID)
+		http.Error(w, "No subtask available", http.StatusNotFound)
+		return
+	}
+
+	// Отправляем подзадачу клиенту
+	json.NewEncoder(w).Encode(sub)
+}
+
+type subtaskResult struct {
+	SubtaskID int     `json:"subtask_id"`
+	Result    float64 `json:"result"`
+}
+
+func receiveTaskResultHandler(w http.ResponseWriter, r *http.Request) {
+	// Распаковываем JSON-данные из запроса
+	var res subtaskResult
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	// Передаем результат подзадачи планировщику
+	onSubtaskComplete(res.SubtaskID, res.Result)
+}
+
+// Другие обработчики для остальных эндпоинтов...
@@ -1,253 +1,150 @@
 package main
 
 import (
-	"encoding/json"
-	"github.com/gorilla/mux"
+	"flag"
 	"log"
 	"net/http"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
-)
+	"os"
+	"os/signal"
+	"syscall"
 
-type Operation struct {
-	Name     string        `json:"name"`
-	Duration time.Duration `json:"duration"`
-}
+	"github.com/gorilla/mux"
 
-type Task struct {
-	ID         int        `json:"id"`
-	Expression string     `json:"expression"`
-	Status     string     `json:"status"`
-	Result     float64    `json:"result,omitempty"`
-	StartTime  time.Time  `json:"start_time,omitempty"`
-	EndTime    time.Time  `json:"end_time,omitempty"`
-	AgentID    int        `json:"agent_id,omitempty"`
-	Mutex      sync.Mutex `json:"-"`
-}
+	"github.com/Kulibyka/YandexL_1_prj/store"
+	"github.com/Kulibyka/YandexL_1_prj/ui"
+)
 
 var (
-	tasks      []*Task
-	operations = []Operation{
-		{"Сложение", 20 * time.Second},
-		{"Вычитание", 30 * time.Second},
-		{"Умножение", 25 * time.Second},
-		{"Деление", 50 * time.Second},
-	}
 	nextTaskID  = 1
-	taskChannel = make(chan *Task, 100)
+	taskChannel = make(chan *store.Subtask, 100)
+	taskStore   store.TaskStore
 )
 
-func addTaskHandler(w http.ResponseWriter, r *http.Request) {
-	// Распаковываем JSON-данные из запроса
-	var task Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-	// Добавляем задачу в список
-	task.ID = nextTaskID
-	nextTaskID++
-	task.Status = "queued"
-	task.Mutex = sync.Mutex{} // Инициализируем Mutex для безопасного доступа к задаче
-	tasks = append(tasks, &task)
-
-	// Отправляем задачу в канал для агента
-	taskChannel <- &task
-
-	// Отвечаем клиенту с ID задачи
-	json.NewEncoder(w).Encode(map[string]int{"task_id": task.ID})
-}
+const defaultDBPath = "tasks.db"
+
+func main() {
+	configPathFlag := flag.String("config", "", "path to operations config file (JSON), hot-reloadable via SIGHUP")
+	flag.Parse()
 
-func getTaskResultHandler(w http.ResponseWriter, r *http.Request) {
-	// Извлекаем ID задачи из URL
-	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/")
-	taskID, err := strconv.Atoi(segments[0])
+	dbPath := os.Getenv("TASKS_DB")
+	if dbPath == "" {
+		dbPath = defaultDBPath
+	}
+	db, err := store.Open(dbPath)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
-		return
+		log.Fatalf("Failed to open task store at %s: %v", dbPath, err)
 	}
+	defer db.Close()
+	taskStore = db
 
-	// Проверяем, существует ли задача с указанным ID
-	if taskID < 1 || taskID > len(tasks) {
-		http.Error(w, "Task not found", http.StatusNotFound)
-		return
+	if err := loadState(); err != nil {
+		log.Fatalf("Failed to reload tasks from store: %v", err)
 	}
 
-	// Получаем задачу по ее ID
-	task := tasks[taskID-1]
-
-	// Блокируем Mutex для безопасного доступа к задаче
-	task.Mutex.Lock()
-	defer task.Mutex.Unlock()
-
-	// Если задача еще не завершена, отправляем сообщение ожидания
-	if task.Status != "completed" {
-		w.WriteHeader(http.StatusAccepted) // Статус код 202 Accepted
-		json.NewEncoder(w).Encode(map[string]string{"status": "Task is not completed yet"})
-		return
+	configPath := *configPathFlag
+	if configPath == "" {
+		configPath = os.Getenv("TASKS_CONFIG")
+	}
+	if configPath != "" {
+		cfg, err := loadOperationsConfigFile(configPath)
+		if err != nil {
+			log.Fatalf("Failed to load operations config %s: %v", configPath, err)
+		}
+		replaceOperations(cfg)
+		log.Printf("operations config loaded from %s: %+v", configPath, cfg)
+		watchOperationsConfigReload(configPath)
 	}
 
-	// Отвечаем клиенту с результатом задачи
-	response := map[string]float64{"result": task.Result}
-	json.NewEncoder(w).Encode(response)
-}
-
-func listTasksHandler(w http.ResponseWriter, r *http.Request) {
-	// Отвечаем клиенту со списком задач
-	json.NewEncoder(w).Encode(tasks)
-}
+	go startAgents(3)
+	startReaper()
 
-func getOperationsHandler(w http.ResponseWriter, r *http.Request) {
-	// Отправляем клиенту список доступных операций со временем их выполнения
-	json.NewEncoder(w).Encode(operations)
-}
+	router := mux.NewRouter()
+	router.HandleFunc("/tasks/add", addTaskHandler).Methods("POST")
+	router.HandleFunc("/tasks", listTasksHandler).Methods("GET")
+	router.HandleFunc("/tasks/{id}/result", getTaskResultHandler).Methods("GET")
+	router.HandleFunc("/tasks/{id}", cancelTaskHandler).Methods("DELETE")
+	router.HandleFunc("/operations", getOperationsHandler).Methods("GET")
+	router.HandleFunc("/operations", replaceOperationsHandler).Methods("PUT")
+	router.HandleFunc("/operations/{name}", updateOperationHandler).Methods("PUT")
+	router.HandleFunc("/tasks/execute", getTaskForExecutionHandler).Methods("GET")
+	router.HandleFunc("/tasks/result", receiveTaskResultHandler).Methods("POST")
+	router.HandleFunc("/agents/register", registerAgentHandler).Methods("POST")
+	router.HandleFunc("/agents/{id}/heartbeat", agentHeartbeatHandler).Methods("POST")
+	router.HandleFunc("/agents", listAgentsHandler).Methods("GET")
 
-func getTaskForExecutionHandler(w http.ResponseWriter, r *http.Request) {
-	// Получаем задачу для выполнения от оркестратора
-	task := <-taskChannel
+	router.HandleFunc("/ui/tasks", ui.TasksHandler(taskStore)).Methods("GET")
+	router.HandleFunc("/ui/tasks/{id}", ui.TaskDetailHandler(taskStore)).Methods("GET")
+	router.HandleFunc("/ui/operations", ui.OperationsHandler(operationProviderFunc(listUIOperations))).Methods("GET")
+	router.HandleFunc("/ui/agents", ui.AgentsHandler(agentProviderFunc(listUIAgents))).Methods("GET")
 
-	// Отправляем задачу клиенту
-	json.NewEncoder(w).Encode(task)
+	log.Println("Server started on :8080")
+	log.Fatal(http.ListenAndServe(":8080", router))
 }
 
-func receiveTaskResultHandler(w http.ResponseWriter, r *http.Request) {
-	// Распаковываем JSON-данные из запроса
-	var task Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-
-	// Находим соответствующую задачу в списке
-	for _, t := range tasks {
-		if t.ID == task.ID {
-			// Обновляем статус и результат задачи
-			t.Status = "completed"
-			t.Result = task.Result
-			t.EndTime = time.Now()
-			break
+// watchOperationsConfigReload запускает горутину, которая по SIGHUP
+// перечитывает конфиг операций из path и атомарно подменяет каталог, не
+// останавливая оркестратор.
+func watchOperationsConfigReload(path string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			cfg, err := loadOperationsConfigFile(path)
+			if err != nil {
+				log.Printf("operations config reload from %s failed: %v", path, err)
+				continue
+			}
+			replaceOperations(cfg)
+			log.Printf("operations config reloaded from %s: %+v", path, cfg)
 		}
-	}
+	}()
 }
 
-// Другие обработчики для остальных эндпоинтов...
-
-// Агент (вычислитель)
-func startAgents(numAgents int) {
-	for i := 0; i < numAgents; i++ {
-		go func(agentID int) {
-			for task := range taskChannel {
-				// Получаем задачу из канала
-				task.Status = "calculated"
-				task.StartTime = time.Now()
-
-				// Выполняем вычисление
-				task.Mutex.Lock()
-				task.Result = evaluateExpression(task.Expression)
-				task.Mutex.Unlock()
-
-				task.Status = "completed"
-				task.EndTime = time.Now()
-			}
-		}(i + 1)
+// loadState перечитывает все задачи из taskStore при старте: поднимает
+// счётчики ID, восстанавливает subtaskIndex и возвращает в taskChannel
+// подзадачи, которые остались в состоянии "queued" или "calculated" после
+// внезапной остановки оркестратора.
+func loadState() error {
+	tasks, err := taskStore.ListTasks()
+	if err != nil {
+		return err
 	}
-}
-
-func evaluateExpression(expression string) float64 {
-	// Удаляем все пробелы из выражения
-	expression = strings.ReplaceAll(expression, " ", "")
 
-	// Создаем стеки для операндов и операторов
-	operandStack := make([]float64, 0)
-	operatorStack := make([]rune, 0)
+	schedulerMutex.Lock()
+	defer schedulerMutex.Unlock()
 
-	// Функция для выполнения операции
-	performOperation := func() {
-		if len(operandStack) < 2 || len(operatorStack) == 0 {
-			return
+	for _, task := range tasks {
+		if task.ID >= nextTaskID {
+			nextTaskID = task.ID + 1
 		}
 
-		b := operandStack[len(operandStack)-1]
-		operandStack = operandStack[:len(operandStack)-1]
-
-		a := operandStack[len(operandStack)-1]
-		operandStack = operandStack[:len(operandStack)-1]
-
-		op := operatorStack[len(operatorStack)-1]
-		operatorStack = operatorStack[:len(operatorStack)-1]
-
-		var result float64
-		switch op {
-		case '+':
-			time.Sleep(operations[0].Duration)
-			result = a + b
-		case '-':
-			time.Sleep(operations[1].Duration)
-			result = a - b
-		case '*':
-			time.Sleep(operations[2].Duration)
-			result = a * b
-		case '/':
-			time.Sleep(operations[3].Duration)
-			result = a / b
-		}
-		operandStack = append(operandStack, result)
-	}
-
-	// Обходим каждый символ в выражении
-	for _, char := range expression {
-		switch char {
-		case '(':
-			operatorStack = append(operatorStack, char)
-		case ')':
-			for len(operatorStack) > 0 && operatorStack[len(operatorStack)-1] != '(' {
-				performOperation()
+		parents := make(map[int]int)
+		for id, sub := range task.Subtasks {
+			if id >= nextSubtaskID {
+				nextSubtaskID = id + 1
 			}
-			if len(operatorStack) > 0 && operatorStack[len(operatorStack)-1] == '(' {
-				operatorStack = operatorStack[:len(operatorStack)-1]
+			if sub.LeftDep != 0 {
+				parents[sub.LeftDep] = id
 			}
-		case '+', '-':
-			for len(operatorStack) > 0 && (operatorStack[len(operatorStack)-1] == '+' || operatorStack[len(operatorStack)-1] == '-' || operatorStack[len(operatorStack)-1] == '*' || operatorStack[len(operatorStack)-1] == '/') {
-				performOperation()
+			if sub.RightDep != 0 {
+				parents[sub.RightDep] = id
 			}
-			operatorStack = append(operatorStack, char)
-		case '*', '/':
-			for len(operatorStack) > 0 && (operatorStack[len(operatorStack)-1] == '*' || operatorStack[len(operatorStack)-1] == '/') {
-				performOperation()
+		}
+		for id := range task.Subtasks {
+			subtaskIndex[id] = &subtaskNode{taskID: task.ID, parentID: parents[id]}
+		}
+
+		for _, sub := range task.Subtasks {
+			if sub.Status == "queued" || sub.Status == "calculated" {
+				sub.Status = "queued"
+				taskChannel <- sub
 			}
-			operatorStack = append(operatorStack, char)
-		default:
-			// Если символ - цифра или точка, добавляем ее в стек операндов
-			operand, _ := strconv.ParseFloat(string(char), 64)
-			operandStack = append(operandStack, operand)
 		}
-	}
 
-	// Выполняем все оставшиеся операции
-	for len(operatorStack) > 0 {
-		performOperation()
+		if task.Status != "completed" && task.Status != "failed" && task.Status != "canceled" {
+			registerTaskContext(task)
+		}
 	}
 
-	// Возвращаем результат
-	return operandStack[0]
-}
-
-func main() {
-
-	go startAgents(3)
-
-	router := mux.NewRouter()
-	router.HandleFunc("/tasks/add", addTaskHandler).Methods("POST")
-	router.HandleFunc("/tasks", listTasksHandler).Methods("GET")
-	router.HandleFunc("/tasks/{id}/result", getTaskResultHandler).Methods("GET")
-	router.HandleFunc("/operations", getOperationsHandler).Methods("GET")
-	router.HandleFunc("/tasks/execute", getTaskForExecutionHandler).Methods("GET")
-	router.HandleFunc("/tasks/result", receiveTaskResultHandler).Methods("POST")
-
-	log.Println("Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	return nil
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind перечисляет виды лексем, которые умеет распознавать tokenize.
+type tokenKind int
+
+const (
+	tokNumber tokenKind = iota
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	num  float64
+}
+
+// tokenize разбивает выражение на лексемы. Поддерживаются многозначные
+// числа и десятичная точка; пробелы игнорируются.
+func tokenize(expression string) ([]token, error) {
+	expression = strings.ReplaceAll(expression, " ", "")
+
+	var tokens []token
+	i := 0
+	for i < len(expression) {
+		c := expression[i]
+		switch {
+		case c == '+':
+			tokens = append(tokens, token{kind: tokPlus})
+			i++
+		case c == '-':
+			tokens = append(tokens, token{kind: tokMinus})
+			i++
+		case c == '*':
+			tokens = append(tokens, token{kind: tokStar})
+			i++
+		case c == '/':
+			tokens = append(tokens, token{kind: tokSlash})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen})
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			seenDot := false
+			for i < len(expression) && (expression[i] >= '0' && expression[i] <= '9' || expression[i] == '.') {
+				if expression[i] == '.' {
+					if seenDot {
+						return nil, fmt.Errorf("unexpected second decimal point at position %d", i)
+					}
+					seenDot = true
+				}
+				i++
+			}
+			num, err := strconv.ParseFloat(expression[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q: %w", expression[start:i], err)
+			}
+			tokens = append(tokens, token{kind: tokNumber, num: num})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF})
+	return tokens, nil
+}
+
+// astNode — узел дерева разбора. Лист хранит число в value, внутренний
+// узел — операцию op и двух детей.
+type astNode struct {
+	op    byte
+	value float64
+	left  *astNode
+	right *astNode
+}
+
+func (n *astNode) isLeaf() bool {
+	return n.left == nil && n.right == nil
+}
+
+// parser реализует рекурентный разбор по грамматике:
+//
+//	expr   := term (('+' | '-') term)*
+//	term   := factor (('*' | '/') factor)*
+//	factor := ('+' | '-') factor | NUMBER | '(' expr ')'
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *parser) parseExpr() (*astNode, error) {
+	node, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokPlus, tokMinus:
+			opTok := p.next()
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			op := byte('+')
+			if opTok.kind == tokMinus {
+				op = '-'
+			}
+			node = &astNode{op: op, left: node, right: right}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *parser) parseTerm() (*astNode, error) {
+	node, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokStar, tokSlash:
+			opTok := p.next()
+			right, err := p.parseFactor()
+			if err != nil {
+				return nil, err
+			}
+			op := byte('*')
+			if opTok.kind == tokSlash {
+				op = '/'
+			}
+			node = &astNode{op: op, left: node, right: right}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// parseFactor разбирает унарный +/-, число или скобочное выражение. Унарный
+// минус превращается в бинарное "0 - x", чтобы дальше по конвейеру (в том
+// числе в Subtask) существовали только бинарные операции.
+func (p *parser) parseFactor() (*astNode, error) {
+	switch p.peek().kind {
+	case tokPlus:
+		p.next()
+		return p.parseFactor()
+	case tokMinus:
+		p.next()
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &astNode{op: '-', left: &astNode{value: 0}, right: operand}, nil
+	case tokNumber:
+		t := p.next()
+		return &astNode{value: t.num}, nil
+	case tokLParen:
+		p.next()
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("expected closing parenthesis")
+		}
+		p.next()
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unexpected token")
+	}
+}
+
+// parseExpression превращает строку выражения в AST, целиком проверяя, что
+// после разбора не осталось лишних символов.
+func parseExpression(expression string) (*astNode, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected trailing input")
+	}
+	return node, nil
+}
+
+// evalAST — чистое (без имитации задержек агента) вычисление дерева,
+// используется юнит-тестами. Выражение из одного литерала scheduleTask
+// завершает сам, беря ast.value.
+func evalAST(n *astNode) (float64, error) {
+	if n.isLeaf() {
+		return n.value, nil
+	}
+	left, err := evalAST(n.left)
+	if err != nil {
+		return 0, err
+	}
+	right, err := evalAST(n.right)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	default:
+		return 0, fmt.Errorf("unknown operator %q", n.op)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func evalExpr(t *testing.T, expr string) float64 {
+	t.Helper()
+	ast, err := parseExpression(expr)
+	if err != nil {
+		t.Fatalf("parseExpression(%q) returned error: %v", expr, err)
+	}
+	result, err := evalAST(ast)
+	if err != nil {
+		t.Fatalf("evalAST(%q) returned error: %v", expr, err)
+	}
+	return result
+}
+
+func TestEvalNestedParens(t *testing.T) {
+	got := evalExpr(t, "((2+3)*4)/5")
+	if want := 4.0; got != want {
+		t.Errorf("((2+3)*4)/5 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalFloatingPointLiterals(t *testing.T) {
+	got := evalExpr(t, "3.5+2.25")
+	if want := 5.75; got != want {
+		t.Errorf("3.5+2.25 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalMixedPrecedence(t *testing.T) {
+	got := evalExpr(t, "2+3*4-5/5")
+	if want := 13.0; got != want {
+		t.Errorf("2+3*4-5/5 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalMultiDigitOperands(t *testing.T) {
+	got := evalExpr(t, "12+34")
+	if want := 46.0; got != want {
+		t.Errorf("12+34 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalUnaryMinus(t *testing.T) {
+	got := evalExpr(t, "-5+3")
+	if want := -2.0; got != want {
+		t.Errorf("-5+3 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalUnaryMinusOnParens(t *testing.T) {
+	got := evalExpr(t, "-(2+3)*2")
+	if want := -10.0; got != want {
+		t.Errorf("-(2+3)*2 = %v, want %v", got, want)
+	}
+}
+
+func TestEvalDivisionByZero(t *testing.T) {
+	ast, err := parseExpression("1/0")
+	if err != nil {
+		t.Fatalf("parseExpression returned error: %v", err)
+	}
+	if _, err := evalAST(ast); err == nil {
+		t.Error("expected division by zero to return an error")
+	}
+}
+
+func TestParseUnexpectedCharacter(t *testing.T) {
+	if _, err := parseExpression("2+@"); err == nil {
+		t.Error("expected parseExpression to reject an unexpected character")
+	}
+}
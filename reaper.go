@@ -0,0 +1,86 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Kulibyka/YandexL_1_prj/store"
+)
+
+const (
+	// subtaskLeaseDuration — на сколько подзадача закрепляется за агентом
+	// при резервировании. Должно с запасом перекрывать самую долгую
+	// операцию (50с на "Деление"), чтобы не отбирать лизинг раньше времени.
+	subtaskLeaseDuration = 90 * time.Second
+	// maxSubtaskAttempts — после скольких резервирований одной и той же
+	// подзадачи без результата задача считается безнадёжной.
+	maxSubtaskAttempts = 3
+	reaperInterval     = 5 * time.Second
+)
+
+// startReaper периодически ищет подзадачи с истёкшим лизингом или агентом,
+// пропустившим heartbeat'ы, и либо возвращает их в очередь, либо — если
+// попытки исчерпаны — переводит владеющую задачу в статус "failed".
+func startReaper() {
+	ticker := time.NewTicker(reaperInterval)
+	go func() {
+		for range ticker.C {
+			reapOnce()
+		}
+	}()
+}
+
+func reapOnce() {
+	// ListTasks здесь — только снимок для поиска кандидатов с истёкшим
+	// лизингом: сама реакция на каждую подзадачу проводится ниже через
+	// RequeueExpiredSubtask одной транзакцией, сверяющей текущее состояние
+	// со снимком, поэтому конкурентное CompleteSubtask/ReserveSubtask,
+	// случившееся между ListTasks и записью, не перезаписывается вслепую.
+	tasks, err := taskStore.ListTasks()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		if task.Status == "completed" || task.Status == "failed" || task.Status == "canceled" {
+			continue
+		}
+
+		for _, sub := range task.Subtasks {
+			if sub.Status != "calculated" {
+				continue
+			}
+
+			expired := !sub.LeaseExpiresAt.IsZero() && now.After(sub.LeaseExpiresAt)
+			if !expired {
+				if agent, ok := agentSnapshot(sub.AgentID); ok && now.Sub(agent.LastHeartbeat) > heartbeatTimeout {
+					expired = true
+				}
+			}
+			if !expired {
+				continue
+			}
+
+			reapSubtask(sub)
+		}
+	}
+}
+
+// reapSubtask атомарно возвращает в очередь (или проваливает, если попытки
+// исчерпаны) одну подзадачу с истёкшим лизингом. sub — снимок из ListTasks;
+// RequeueExpiredSubtask сверяет его с актуальным состоянием внутри
+// транзакции и не трогает подзадачу, если она успела измениться конкурентно
+// (ok == false).
+func reapSubtask(sub *store.Subtask) {
+	owner, ok, err := taskStore.RequeueExpiredSubtask(sub.ID, sub.AgentID, sub.LeaseExpiresAt, sub.Attempts, maxSubtaskAttempts)
+	if err != nil || !ok {
+		return
+	}
+	if owner.Status == "failed" {
+		releaseTaskContext(owner.ID)
+		return
+	}
+	if queued, ok := owner.Subtasks[sub.ID]; ok {
+		taskChannel <- queued
+	}
+}
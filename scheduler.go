@@ -0,0 +1,175 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Kulibyka/YandexL_1_prj/store"
+)
+
+// subtaskNode хранит чисто структурную информацию о месте подзадачи в
+// дереве: какой задаче она принадлежит и кто её родитель. Статус, значения
+// операндов и прочие изменяемые поля здесь не кэшируются — единственный
+// источник истины для них taskStore, чтобы конкурентные изменения соседних
+// подзадач не терялись при перезаписи устаревшего снимка. Живёт только в
+// памяти и перестраивается из store.Task.Subtasks при старте (см. loadState
+// в main.go).
+type subtaskNode struct {
+	taskID   int
+	parentID int // 0, если подзадача — корень дерева
+}
+
+var (
+	schedulerMutex sync.Mutex
+	subtaskIndex   = make(map[int]*subtaskNode)
+	nextSubtaskID  = 1
+)
+
+// scheduleTask разбирает выражение задачи в AST и либо завершает задачу
+// сразу (если выражение — один литерал), либо строит дерево подзадач,
+// сохраняет задачу в taskStore и ставит в очередь подзадачи, чьи операнды
+// уже готовы.
+func scheduleTask(task *store.Task) {
+	ast, err := parseExpression(task.Expression)
+	if err != nil {
+		task.Status = "failed"
+		task.Error = err.Error()
+		taskStore.UpdateTask(task)
+		return
+	}
+
+	if ast.isLeaf() {
+		task.Status = "completed"
+		task.Result = ast.value
+		task.EndTime = time.Now()
+		taskStore.UpdateTask(task)
+		return
+	}
+
+	schedulerMutex.Lock()
+	defer schedulerMutex.Unlock()
+
+	task.Subtasks = make(map[int]*store.Subtask)
+	task.RootSubtaskID = buildSubtasks(task, ast)
+	task.Status = "queued"
+	task.StartTime = time.Now()
+
+	// Подзадачи, чьи операнды — листья, помечаем "queued" прямо в дереве
+	// до первой записи в taskStore: иначе там навсегда останется "pending",
+	// ReserveSubtask их не увидит, и сигнал из канала пропадёт впустую.
+	markReady(task)
+	taskStore.UpdateTask(task)
+	registerTaskContext(task)
+	enqueueReady(task)
+}
+
+// buildSubtasks рекурсивно создаёт по одной Subtask на каждый оператор
+// дерева и связывает их через LeftDep/RightDep; листья становятся
+// готовыми литеральными операндами без отдельной подзадачи.
+func buildSubtasks(task *store.Task, node *astNode) int {
+	id := nextSubtaskID
+	nextSubtaskID++
+
+	sub := &store.Subtask{ID: id, TaskID: task.ID, Op: node.op, Status: "pending"}
+
+	if node.left.isLeaf() {
+		sub.LeftValue = node.left.value
+		sub.LeftReady = true
+	} else {
+		depID := buildSubtasks(task, node.left)
+		sub.LeftDep = depID
+		task.Subtasks[depID].ParentID = id
+		subtaskIndex[depID].parentID = id
+	}
+
+	if node.right.isLeaf() {
+		sub.RightValue = node.right.value
+		sub.RightReady = true
+	} else {
+		depID := buildSubtasks(task, node.right)
+		sub.RightDep = depID
+		task.Subtasks[depID].ParentID = id
+		subtaskIndex[depID].parentID = id
+	}
+
+	task.Subtasks[id] = sub
+	subtaskIndex[id] = &subtaskNode{taskID: task.ID, parentID: 0}
+	return id
+}
+
+// markReady переводит в "queued" все подзадачи дерева, чьи операнды уже
+// готовы (листовые операнды), не трогая taskChannel — вызывается до первой
+// записи задачи в taskStore, чтобы там сразу оказался правильный статус, а
+// не "pending", который ReserveSubtask никогда не заберёт.
+func markReady(task *store.Task) {
+	for _, sub := range task.Subtasks {
+		if sub.Status == "pending" && sub.LeftReady && sub.RightReady {
+			sub.Status = "queued"
+		}
+	}
+}
+
+// enqueueReady ставит в taskChannel подзадачи дерева, уже находящиеся в
+// статусе "queued" в taskStore. Вызывающий код должен держать
+// schedulerMutex.
+func enqueueReady(task *store.Task) {
+	for _, sub := range task.Subtasks {
+		if sub.Status == "queued" {
+			taskChannel <- sub
+		}
+	}
+}
+
+// onSubtaskComplete фиксирует результат подзадачи. taskStore.CompleteSubtask
+// делает это одной атомарной транзакцией: читает владеющую задачу заново из
+// хранилища, распространяет результат родителю и, если это был корень
+// дерева, завершает саму задачу — благодаря этому конкурентное
+// ReserveSubtask соседней подзадачи в той же задаче никогда не перезаписывается
+// устаревшим in-memory снимком.
+//
+// Перед записью результата проверяем context задачи: runAgent уже не
+// пропускает сюда отменённые подзадачи, но это единственный путь для
+// внешнего агента через POST /tasks/result, который никак иначе не узнал
+// бы, что задачу тем временем отменили через DELETE /tasks/{id} или она
+// истекла по дедлайну.
+func onSubtaskComplete(subtaskID int, result float64) {
+	schedulerMutex.Lock()
+	node, ok := subtaskIndex[subtaskID]
+	schedulerMutex.Unlock()
+	if ok && taskContextFor(node.taskID).Err() != nil {
+		abandonSubtask(subtaskID)
+		return
+	}
+
+	_, owner, readyParent, err := taskStore.CompleteSubtask(subtaskID, result)
+	if err != nil {
+		return
+	}
+
+	if owner.Status == "completed" {
+		releaseTaskContext(owner.ID)
+		return
+	}
+	if readyParent != nil {
+		taskChannel <- readyParent
+	}
+}
+
+// abandonSubtask помечает уже зарезервированную подзадачу отменённой вместо
+// выполнения — её забрал агент, но owning-задача тем временем истекла по
+// дедлайну или была отменена через DELETE /tasks/{id}.
+func abandonSubtask(subtaskID int) {
+	taskStore.AbandonSubtask(subtaskID)
+}
+
+// failSubtask переводит подзадачу и владеющую ею задачу в "failed" — в
+// отличие от abandonSubtask, который помечает "canceled" лишь саму подзадачу
+// уже отменённой задачи, это сигнал, что саму операцию выполнить невозможно
+// (например, деление на ноль).
+func failSubtask(subtaskID int, reason error) {
+	owner, err := taskStore.FailSubtask(subtaskID, reason.Error())
+	if err != nil {
+		return
+	}
+	releaseTaskContext(owner.ID)
+}
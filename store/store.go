@@ -0,0 +1,446 @@
+// Package store делает очередь задач и дерево подзадач переживаемыми
+// рестарт оркестратора: всё, что раньше жило только в tasks/taskChannel,
+// теперь также лежит в embedded KV-базе (bbolt) и перечитывается при старте.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Task — выражение, присланное клиентом через /tasks/add, вместе с деревом
+// подзадач, на которые оно было разложено.
+type Task struct {
+	ID            int              `json:"id"`
+	Expression    string           `json:"expression"`
+	Status        string           `json:"status"`
+	Result        float64          `json:"result,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	StartTime     time.Time        `json:"start_time,omitempty"`
+	EndTime       time.Time        `json:"end_time,omitempty"`
+	AgentID       int              `json:"agent_id,omitempty"`
+	Deadline      time.Time        `json:"deadline,omitempty"`
+	Subtasks      map[int]*Subtask `json:"subtasks,omitempty"`
+	RootSubtaskID int              `json:"root_subtask_id,omitempty"`
+}
+
+// Subtask — один узел дерева выражения, соответствующий ровно одной
+// бинарной операции. Поля LeftReady/RightReady участвуют в сериализации,
+// чтобы после рестарта оркестратор мог восстановить, какие подзадачи уже
+// готовы к выполнению, не пересобирая AST заново.
+type Subtask struct {
+	ID             int       `json:"id"`
+	TaskID         int       `json:"task_id"`
+	Op             byte      `json:"op"`
+	LeftValue      float64   `json:"left_value"`
+	RightValue     float64   `json:"right_value"`
+	LeftDep        int       `json:"left_dep,omitempty"`
+	RightDep       int       `json:"right_dep,omitempty"`
+	LeftReady      bool      `json:"left_ready"`
+	RightReady     bool      `json:"right_ready"`
+	ParentID       int       `json:"parent_id,omitempty"`
+	Result         float64   `json:"result,omitempty"`
+	Status         string    `json:"status"`
+	AgentID        int       `json:"agent_id,omitempty"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at,omitempty"`
+	Attempts       int       `json:"attempts,omitempty"`
+}
+
+// TaskStore — хранилище задач, атомарное относительно резервирования
+// подзадачи агентом и фиксации её результата.
+type TaskStore interface {
+	CreateTask(task *Task) error
+	UpdateTask(task *Task) error
+	GetTask(id int) (*Task, bool, error)
+	ListTasks() ([]*Task, error)
+	// ReserveSubtask атомарно находит первую подзадачу в статусе "queued",
+	// переводит её в "calculated" и закрепляет за agentID на срок lease —
+	// это не даёт двум агентам одновременно забрать одну и ту же подзадачу.
+	ReserveSubtask(agentID int, lease time.Duration) (*Subtask, bool, error)
+	// CompleteSubtask атомарно помечает подзадачу завершённой, в той же
+	// транзакции распространяет результат родителю (если он есть) и, если
+	// это был корень дерева, переводит саму задачу в "completed". Родитель
+	// и задача читаются заново из хранилища внутри транзакции, поэтому
+	// конкурентное ReserveSubtask соседней подзадачи не может быть потеряно.
+	// readyParent ненулевой, только если родитель стал готов к постановке в
+	// очередь именно этим вызовом.
+	CompleteSubtask(subtaskID int, result float64) (completed *Subtask, owner *Task, readyParent *Subtask, err error)
+	// AbandonSubtask атомарно возвращает зарезервированную подзадачу в
+	// "canceled" — её забрал агент, но владеющая задача уже отменена или
+	// истекла по дедлайну.
+	AbandonSubtask(subtaskID int) (*Task, error)
+	// FailSubtask атомарно переводит и подзадачу, и владеющую ею задачу в
+	// "failed" — операцию в принципе невозможно выполнить (например,
+	// деление на ноль), в отличие от AbandonSubtask, который помечает
+	// "canceled" лишь саму подзадачу, не трогая статус задачи.
+	FailSubtask(subtaskID int, reason string) (*Task, error)
+	// RequeueExpiredSubtask атомарно возвращает в очередь подзадачу с
+	// истёкшим лизингом или — если попытки исчерпаны — переводит её и
+	// владеющую задачу в "failed". agentID/leaseExpiresAt/attempts — это
+	// снимок подзадачи на момент, когда reaper счёл её истёкшей; если к
+	// началу транзакции подзадача уже не совпадает с этим снимком (успела
+	// завершиться или была переподтверждена заново), ok возвращается false
+	// и подзадача не трогается — так reaper никогда не перезаписывает более
+	// свежее состояние, прилетевшее конкурентно через CompleteSubtask.
+	RequeueExpiredSubtask(subtaskID, agentID int, leaseExpiresAt time.Time, attempts, maxAttempts int) (owner *Task, ok bool, err error)
+	// CancelTask атомарно переводит задачу в "canceled" с указанной
+	// причиной, если она ещё не находится в одном из финальных статусов.
+	CancelTask(taskID int, reason string) (task *Task, canceled bool, err error)
+}
+
+var tasksBucket = []byte("tasks")
+
+// BoltStore — реализация TaskStore поверх embedded KV-базы bbolt.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// Open открывает (создавая при необходимости) файл базы данных по path и
+// готовит нужные bucket'ы.
+func Open(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init buckets: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close закрывает базу данных.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func taskKey(id int) []byte {
+	return []byte(strconv.Itoa(id))
+}
+
+func (s *BoltStore) put(task *Task) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(tasksBucket).Put(taskKey(task.ID), data)
+	})
+}
+
+func (s *BoltStore) CreateTask(task *Task) error {
+	return s.put(task)
+}
+
+func (s *BoltStore) UpdateTask(task *Task) error {
+	return s.put(task)
+}
+
+func (s *BoltStore) GetTask(id int) (*Task, bool, error) {
+	var task *Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(tasksBucket).Get(taskKey(id))
+		if data == nil {
+			return nil
+		}
+		task = &Task{}
+		return json.Unmarshal(data, task)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return task, task != nil, nil
+}
+
+func (s *BoltStore) ListTasks() ([]*Task, error) {
+	var result []*Task
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, v []byte) error {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			result = append(result, task)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (s *BoltStore) ReserveSubtask(agentID int, lease time.Duration) (*Subtask, bool, error) {
+	var reserved *Subtask
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			for _, sub := range task.Subtasks {
+				if sub.Status != "queued" {
+					continue
+				}
+				sub.Status = "calculated"
+				sub.AgentID = agentID
+				sub.Attempts++
+				sub.LeaseExpiresAt = time.Now().Add(lease)
+				reserved = sub
+				data, err := json.Marshal(task)
+				if err != nil {
+					return err
+				}
+				return b.Put(k, data)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return reserved, reserved != nil, nil
+}
+
+func (s *BoltStore) CompleteSubtask(subtaskID int, result float64) (*Subtask, *Task, *Subtask, error) {
+	var completed, readyParent *Subtask
+	var owner *Task
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			sub, ok := task.Subtasks[subtaskID]
+			if !ok {
+				continue
+			}
+
+			sub.Status = "completed"
+			sub.Result = result
+
+			if sub.ParentID != 0 {
+				if parent, ok := task.Subtasks[sub.ParentID]; ok {
+					if parent.LeftDep == subtaskID {
+						parent.LeftValue = result
+						parent.LeftReady = true
+					}
+					if parent.RightDep == subtaskID {
+						parent.RightValue = result
+						parent.RightReady = true
+					}
+					if parent.Status == "pending" && parent.LeftReady && parent.RightReady {
+						parent.Status = "queued"
+						readyParent = parent
+					}
+				}
+			} else if subtaskID == task.RootSubtaskID {
+				task.Status = "completed"
+				task.Result = result
+				task.EndTime = time.Now()
+			}
+
+			completed = sub
+			owner = task
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			return b.Put(k, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if completed == nil {
+		return nil, nil, nil, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return completed, owner, readyParent, nil
+}
+
+// AbandonSubtask атомарно переводит зарезервированную подзадачу в
+// "canceled", не трогая остальное дерево — владеющая задача дочитывается
+// заново из хранилища в той же транзакции по тем же причинам, что и в
+// CompleteSubtask.
+func (s *BoltStore) AbandonSubtask(subtaskID int) (*Task, error) {
+	var owner *Task
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			sub, ok := task.Subtasks[subtaskID]
+			if !ok {
+				continue
+			}
+			sub.Status = "canceled"
+			owner = task
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			return b.Put(k, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return nil, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return owner, nil
+}
+
+// FailSubtask атомарно переводит подзадачу и владеющую ею задачу в
+// "failed" с указанной причиной.
+func (s *BoltStore) FailSubtask(subtaskID int, reason string) (*Task, error) {
+	var owner *Task
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			sub, ok := task.Subtasks[subtaskID]
+			if !ok {
+				continue
+			}
+			sub.Status = "failed"
+			task.Status = "failed"
+			task.Error = reason
+			task.EndTime = time.Now()
+			owner = task
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			return b.Put(k, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return nil, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return owner, nil
+}
+
+// RequeueExpiredSubtask сверяет подзадачу с переданным снимком
+// (agentID/leaseExpiresAt/attempts) и, только если она всё ещё в статусе
+// "calculated" и не изменилась с момента снимка, либо возвращает её в
+// очередь, либо — если attempts достигли maxAttempts — проваливает и её,
+// и владеющую задачу.
+func (s *BoltStore) RequeueExpiredSubtask(subtaskID, agentID int, leaseExpiresAt time.Time, attempts, maxAttempts int) (*Task, bool, error) {
+	var owner *Task
+	var changed bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			task := &Task{}
+			if err := json.Unmarshal(v, task); err != nil {
+				return err
+			}
+			sub, ok := task.Subtasks[subtaskID]
+			if !ok {
+				continue
+			}
+			owner = task
+			if sub.Status != "calculated" || sub.AgentID != agentID ||
+				!sub.LeaseExpiresAt.Equal(leaseExpiresAt) || sub.Attempts != attempts {
+				// Подзадача уже изменилась конкурентно (завершилась или
+				// была переподтверждена заново) — снимок reaper'а устарел,
+				// трогать нечего.
+				return nil
+			}
+
+			if attempts >= maxAttempts {
+				sub.Status = "failed"
+				task.Status = "failed"
+				task.Error = "subtask exceeded retry limit"
+				task.EndTime = time.Now()
+			} else {
+				sub.Status = "queued"
+				sub.AgentID = 0
+				sub.LeaseExpiresAt = time.Time{}
+			}
+			changed = true
+			owner = task
+			data, err := json.Marshal(task)
+			if err != nil {
+				return err
+			}
+			return b.Put(k, data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if owner == nil {
+		return nil, false, fmt.Errorf("subtask %d not found", subtaskID)
+	}
+	return owner, changed, nil
+}
+
+// CancelTask атомарно переводит задачу в "canceled", если она ещё не
+// находится в одном из финальных статусов — читает и пишет её одной
+// транзакцией по ключу, чтобы не потерять конкурентное завершение
+// подзадачи, совпавшее по времени со срабатыванием дедлайна.
+func (s *BoltStore) CancelTask(taskID int, reason string) (*Task, bool, error) {
+	var task *Task
+	var canceled bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(tasksBucket)
+		data := b.Get(taskKey(taskID))
+		if data == nil {
+			return nil
+		}
+		task = &Task{}
+		if err := json.Unmarshal(data, task); err != nil {
+			return err
+		}
+		if task.Status == "completed" || task.Status == "failed" || task.Status == "canceled" {
+			return nil
+		}
+		task.Status = "canceled"
+		task.Error = reason
+		task.EndTime = time.Now()
+		canceled = true
+		out, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		return b.Put(taskKey(taskID), out)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if task == nil {
+		return nil, false, fmt.Errorf("task %d not found", taskID)
+	}
+	return task, canceled, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Operation описывает одну арифметическую операцию и время, которое агент
+// должен потратить на её имитацию.
+type Operation struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// operationOrder задаёт порядок операций в GET /operations и набор операций,
+// обязательных в конфиге (см. validateOperationsConfig); соответствие между
+// символом оператора в выражении и именем операции задаёт opName.
+var operationOrder = []string{"Сложение", "Вычитание", "Умножение", "Деление"}
+
+var (
+	operationsMu sync.RWMutex
+	operations   = map[string]time.Duration{
+		"Сложение":  20 * time.Second,
+		"Вычитание": 30 * time.Second,
+		"Умножение": 25 * time.Second,
+		"Деление":   50 * time.Second,
+	}
+)
+
+func opName(op byte) string {
+	switch op {
+	case '+':
+		return "Сложение"
+	case '-':
+		return "Вычитание"
+	case '*':
+		return "Умножение"
+	case '/':
+		return "Деление"
+	default:
+		return ""
+	}
+}
+
+// operationDuration возвращает текущую длительность операции по символу
+// оператора.
+func operationDuration(op byte) time.Duration {
+	operationsMu.RLock()
+	defer operationsMu.RUnlock()
+	return operations[opName(op)]
+}
+
+// listOperations возвращает каталог операций в стабильном порядке — для
+// GET /operations и дашборда.
+func listOperations() []Operation {
+	operationsMu.RLock()
+	defer operationsMu.RUnlock()
+	result := make([]Operation, 0, len(operationOrder))
+	for _, name := range operationOrder {
+		result = append(result, Operation{Name: name, Duration: operations[name]})
+	}
+	return result
+}
+
+// setOperationDuration обновляет длительность уже существующей операции;
+// возвращает false, если операция с таким именем не заведена.
+func setOperationDuration(name string, duration time.Duration) bool {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	if _, ok := operations[name]; !ok {
+		return false
+	}
+	operations[name] = duration
+	return true
+}
+
+// replaceOperations атомарно подменяет весь каталог операций — его
+// использует и PUT /operations, и перечитывание конфига по SIGHUP.
+func replaceOperations(cfg map[string]time.Duration) {
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	for name, duration := range cfg {
+		operations[name] = duration
+	}
+}
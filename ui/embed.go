@@ -0,0 +1,6 @@
+package ui
+
+import "embed"
+
+//go:embed templates/*.html
+var templateFS embed.FS
@@ -0,0 +1,105 @@
+// Package ui рендерит html/template-дашборд оркестратора: список задач,
+// карточку задачи, редактор длительностей операций и список агентов.
+// Шаблоны вшиты в бинарник через embed.FS, чтобы деплой оставался
+// однофайловым, как и раньше.
+package ui
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Kulibyka/YandexL_1_prj/store"
+)
+
+// AgentInfo — то немногое об агенте, что нужно показать оператору; своя
+// структура вместо main.Agent, чтобы ui не зависел от пакета main.
+type AgentInfo struct {
+	ID            int
+	RegisteredAt  time.Time
+	LastHeartbeat time.Time
+}
+
+// OperationInfo — операция и её текущая длительность.
+type OperationInfo struct {
+	Name     string
+	Duration time.Duration
+}
+
+// TaskProvider — то, что от хранилища задач требуется дашборду. Реализуется
+// напрямую store.TaskStore.
+type TaskProvider interface {
+	ListTasks() ([]*store.Task, error)
+	GetTask(id int) (*store.Task, bool, error)
+}
+
+// AgentProvider отдаёт текущий снимок зарегистрированных агентов.
+type AgentProvider interface {
+	ListAgents() []AgentInfo
+}
+
+// OperationProvider отдаёт текущий каталог операций с их длительностями.
+type OperationProvider interface {
+	ListOperations() []OperationInfo
+}
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+func render(w http.ResponseWriter, title string, data map[string]interface{}) {
+	data["Title"] = title
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, "base", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// TasksHandler отдаёт /ui/tasks — список всех задач.
+func TasksHandler(tasks TaskProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := tasks.ListTasks()
+		if err != nil {
+			http.Error(w, "Failed to list tasks", http.StatusInternalServerError)
+			return
+		}
+		render(w, "Задачи", map[string]interface{}{"Tasks": list})
+	}
+}
+
+// TaskDetailHandler отдаёт /ui/tasks/{id} — выражение, дерево подзадач,
+// тайминги и результат одной задачи.
+func TaskDetailHandler(tasks TaskProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+		task, found, err := tasks.GetTask(id)
+		if err != nil {
+			http.Error(w, "Failed to load task", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		render(w, "Задача #"+strconv.Itoa(id), map[string]interface{}{"Task": task})
+	}
+}
+
+// OperationsHandler отдаёт /ui/operations — инлайн-редактор длительностей.
+func OperationsHandler(ops OperationProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render(w, "Операции", map[string]interface{}{"Operations": ops.ListOperations()})
+	}
+}
+
+// AgentsHandler отдаёт /ui/agents — список агентов с последним heartbeat.
+func AgentsHandler(agents AgentProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render(w, "Агенты", map[string]interface{}{"Agents": agents.ListAgents()})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "github.com/Kulibyka/YandexL_1_prj/ui"
+
+// operationProviderFunc адаптирует listUIOperations под ui.OperationProvider
+// без отдельного именованного типа с полями.
+type operationProviderFunc func() []ui.OperationInfo
+
+func (f operationProviderFunc) ListOperations() []ui.OperationInfo {
+	return f()
+}
+
+func listUIOperations() []ui.OperationInfo {
+	ops := listOperations()
+	result := make([]ui.OperationInfo, 0, len(ops))
+	for _, op := range ops {
+		result = append(result, ui.OperationInfo{Name: op.Name, Duration: op.Duration})
+	}
+	return result
+}
+
+// agentProviderFunc адаптирует listUIAgents под ui.AgentProvider.
+type agentProviderFunc func() []ui.AgentInfo
+
+func (f agentProviderFunc) ListAgents() []ui.AgentInfo {
+	return f()
+}
+
+func listUIAgents() []ui.AgentInfo {
+	agentsMutex.Lock()
+	defer agentsMutex.Unlock()
+
+	result := make([]ui.AgentInfo, 0, len(agents))
+	for _, agent := range agents {
+		result = append(result, ui.AgentInfo{
+			ID:            agent.ID,
+			RegisteredAt:  agent.RegisteredAt,
+			LastHeartbeat: agent.LastHeartbeat,
+		})
+	}
+	return result
+}